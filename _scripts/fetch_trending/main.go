@@ -1,25 +1,72 @@
 package main
 
 import (
+    "context"
     "flag"
     "fmt"
     "os"
+    "os/signal"
     "strings"
+    "syscall"
     "time"
 
     "neodb-trending-history/_scripts/trending"
 )
 
+// outputFlags collects repeated `-output` occurrences, e.g.
+// `-output type=local,dest=./data -output type=gzip,dest=./data`.
+type outputFlags []string
+
+func (o *outputFlags) String() string { return strings.Join(*o, " ") }
+
+func (o *outputFlags) Set(spec string) error {
+    *o = append(*o, spec)
+    return nil
+}
+
 func main() {
+    args := os.Args[1:]
+    cmd := "fetch"
+    if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+        cmd = args[0]
+        args = args[1:]
+    }
+
+    switch cmd {
+    case "fetch":
+        runFetch(args)
+    case "serve":
+        runServe(args)
+    case "report":
+        runReport(args)
+    default:
+        fmt.Fprintf(os.Stderr, "unknown subcommand %q (want fetch, serve, or report)\n", cmd)
+        os.Exit(2)
+    }
+}
+
+func runFetch(args []string) {
     cfg := trending.DefaultConfig()
+    fs := flag.NewFlagSet("fetch", flag.ExitOnError)
 
     var typesCSV string
-    flag.StringVar(&cfg.InstancesFile, "instances", cfg.InstancesFile, "path to instances.txt (one host per line)")
-    flag.StringVar(&cfg.OutputRoot, "out", cfg.OutputRoot, "output root directory")
-    flag.StringVar(&typesCSV, "types", strings.Join(cfg.Types, ","), "comma-separated trending types")
-    flag.DurationVar(&cfg.HTTPTimeout, "timeout", cfg.HTTPTimeout, "HTTP timeout")
-    flag.StringVar(&cfg.UserAgent, "ua", cfg.UserAgent, "HTTP User-Agent header")
-    flag.Parse()
+    var outputs outputFlags
+    fs.StringVar(&cfg.InstancesFile, "instances", cfg.InstancesFile, "path to instances.txt (one host per line)")
+    fs.StringVar(&cfg.OutputRoot, "out", cfg.OutputRoot, "output root directory (payloads go here when -output is not given; the dedup index and host metadata are always kept here)")
+    fs.StringVar(&typesCSV, "types", strings.Join(cfg.Types, ","), "comma-separated trending types")
+    fs.DurationVar(&cfg.HTTPTimeout, "timeout", cfg.HTTPTimeout, "HTTP timeout")
+    fs.StringVar(&cfg.UserAgent, "ua", cfg.UserAgent, "HTTP User-Agent header")
+    fs.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "number of hosts fetched in parallel")
+    fs.IntVar(&cfg.PerHostConcurrency, "per-host-concurrency", cfg.PerHostConcurrency, "number of types fetched in parallel per host")
+    fs.Float64Var(&cfg.RateLimit, "rate-limit", cfg.RateLimit, "max requests/sec per host (0 disables limiting)")
+    fs.BoolVar(&cfg.Dedup, "dedup", cfg.Dedup, "skip writing a new snapshot when unchanged from the previous one")
+    fs.IntVar(&cfg.MaxRetries, "max-retries", cfg.MaxRetries, "max attempts per request, including the first (1 disables retries)")
+    fs.DurationVar(&cfg.RetryBaseDelay, "retry-base-delay", cfg.RetryBaseDelay, "backoff before the first retry, doubling (with jitter) each attempt")
+    fs.IntVar(&cfg.CircuitThreshold, "circuit-threshold", cfg.CircuitThreshold, "consecutive request failures before skipping a host's remaining types (0 disables)")
+    fs.Var(&outputs, "output", "output sink spec, repeatable (e.g. type=local,dest=./data or type=tar,dest=snapshot.tar or type=gzip,dest=./data or type=s3,bucket=...,prefix=...,region=...)")
+    fs.Parse(args)
+
+    cfg.OutputSpecs = outputs
 
     if typesCSV != "" {
         parts := strings.Split(typesCSV, ",")
@@ -32,14 +79,74 @@ func main() {
         }
     }
 
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
     started := time.Now()
     logf := func(format string, args ...any) {
         fmt.Fprintf(os.Stdout, format+"\n", args...)
     }
 
-    if err := trending.FetchAll(cfg, logf); err != nil {
+    if err := trending.FetchAll(ctx, cfg, logf); err != nil {
         fmt.Fprintf(os.Stderr, "fetch failed: %v\n", err)
         os.Exit(1)
     }
     logf("done in %s", time.Since(started).Truncate(time.Millisecond))
 }
+
+func runReport(args []string) {
+    cfg := trending.DefaultConfig()
+    fs := flag.NewFlagSet("report", flag.ExitOnError)
+
+    var typesCSV string
+    root := fs.String("out", ".", "output root directory to read snapshots from")
+    host := fs.String("host", "", "report on a single dashified host (default: every host under -out)")
+    days := fs.Int("days", 7, "size of the lookback window, in days")
+    limit := fs.Int("limit", 10, "max movers to report per type")
+    fs.StringVar(&typesCSV, "types", strings.Join(cfg.Types, ","), "comma-separated trending types")
+    fs.Parse(args)
+
+    types := cfg.Types
+    if typesCSV != "" {
+        types = nil
+        for _, p := range strings.Split(typesCSV, ",") {
+            if p = strings.TrimSpace(p); p != "" {
+                types = append(types, p)
+            }
+        }
+    }
+
+    hosts := []string{*host}
+    if *host == "" {
+        var err error
+        hosts, err = trending.ListHosts(*root)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "list hosts: %v\n", err)
+            os.Exit(1)
+        }
+    }
+
+    to := time.Now().UTC()
+    from := to.AddDate(0, 0, -*days)
+    for _, h := range hosts {
+        if err := trending.GenerateReport(*root, h, types, from, to, *limit); err != nil {
+            fmt.Fprintf(os.Stderr, "report %s: %v\n", h, err)
+            continue
+        }
+        fmt.Fprintf(os.Stdout, "wrote %s/MOVERS.md\n", h)
+    }
+}
+
+func runServe(args []string) {
+    fs := flag.NewFlagSet("serve", flag.ExitOnError)
+    root := fs.String("out", ".", "output root directory to browse")
+    addr := fs.String("addr", ":8080", "address to listen on")
+    fs.Parse(args)
+
+    srv := trending.NewServer(*root)
+    fmt.Fprintf(os.Stdout, "serving %s on %s\n", *root, *addr)
+    if err := srv.ListenAndServe(*addr); err != nil {
+        fmt.Fprintf(os.Stderr, "serve failed: %v\n", err)
+        os.Exit(1)
+    }
+}
@@ -0,0 +1,128 @@
+package trending
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+)
+
+// concurrencyProbe records how many requests are in flight at once, so tests
+// can assert FetchAll never exceeds a configured bound.
+type concurrencyProbe struct {
+    mu      sync.Mutex
+    inFlt   int
+    maxInFl int
+}
+
+func (p *concurrencyProbe) handler(hold time.Duration) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        p.mu.Lock()
+        p.inFlt++
+        if p.inFlt > p.maxInFl {
+            p.maxInFl = p.inFlt
+        }
+        p.mu.Unlock()
+
+        time.Sleep(hold)
+
+        p.mu.Lock()
+        p.inFlt--
+        p.mu.Unlock()
+        w.Write([]byte("[]"))
+    }
+}
+
+func (p *concurrencyProbe) max() int {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.maxInFl
+}
+
+func writeInstancesFile(t *testing.T, hosts []string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "instances.txt")
+    var body string
+    for _, h := range hosts {
+        body += h + "\n"
+    }
+    if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+// withHTTPFetchScheme points fetchType at a plain httptest.Server for the
+// duration of the test, avoiding the need to trust a self-signed cert inside
+// FetchAll's own *http.Client.
+func withHTTPFetchScheme(t *testing.T) {
+    t.Helper()
+    prev := fetchScheme
+    fetchScheme = "http"
+    t.Cleanup(func() { fetchScheme = prev })
+}
+
+// TestFetchAllRespectsHostConcurrency checks that Config.Concurrency bounds
+// how many hosts are fetched in parallel: with 6 hosts, Concurrency 2 and a
+// single type per host, the peak number of requests in flight should be
+// exactly 2: never more, and the pool should actually fill up.
+func TestFetchAllRespectsHostConcurrency(t *testing.T) {
+    withHTTPFetchScheme(t)
+    probe := &concurrencyProbe{}
+    srv := httptest.NewServer(probe.handler(30 * time.Millisecond))
+    defer srv.Close()
+
+    var hosts []string
+    for i := 0; i < 6; i++ {
+        hosts = append(hosts, srv.Listener.Addr().String())
+    }
+
+    cfg := DefaultConfig()
+    cfg.InstancesFile = writeInstancesFile(t, hosts)
+    cfg.OutputRoot = t.TempDir()
+    cfg.Types = []string{"book"}
+    cfg.Concurrency = 2
+    cfg.PerHostConcurrency = 1
+    cfg.RateLimit = 0
+    cfg.Dedup = false
+    cfg.CircuitThreshold = 0
+
+    if err := FetchAll(context.Background(), cfg, nil); err != nil {
+        t.Fatalf("FetchAll: %v", err)
+    }
+    if got := probe.max(); got != cfg.Concurrency {
+        t.Errorf("max concurrent requests = %d, want %d (Config.Concurrency)", got, cfg.Concurrency)
+    }
+}
+
+// TestFetchAllRespectsPerHostConcurrency checks that Config.PerHostConcurrency
+// bounds how many types are fetched in parallel within a single host: with one
+// host, six types, Concurrency 1 and PerHostConcurrency 3, the peak number of
+// requests in flight should be exactly 3.
+func TestFetchAllRespectsPerHostConcurrency(t *testing.T) {
+    withHTTPFetchScheme(t)
+    probe := &concurrencyProbe{}
+    srv := httptest.NewServer(probe.handler(30 * time.Millisecond))
+    defer srv.Close()
+
+    cfg := DefaultConfig()
+    cfg.InstancesFile = writeInstancesFile(t, []string{srv.Listener.Addr().String()})
+    cfg.OutputRoot = t.TempDir()
+    cfg.Types = []string{"book", "movie", "tv", "music", "game", "podcast"}
+    cfg.Concurrency = 1
+    cfg.PerHostConcurrency = 3
+    cfg.RateLimit = 0
+    cfg.Dedup = false
+    cfg.CircuitThreshold = 0
+
+    if err := FetchAll(context.Background(), cfg, nil); err != nil {
+        t.Fatalf("FetchAll: %v", err)
+    }
+    if got := probe.max(); got != cfg.PerHostConcurrency {
+        t.Errorf("max concurrent requests = %d, want %d (Config.PerHostConcurrency)", got, cfg.PerHostConcurrency)
+    }
+}
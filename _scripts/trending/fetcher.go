@@ -3,16 +3,21 @@ package trending
 import (
     "bufio"
     "context"
+    "crypto/sha256"
+    "encoding/hex"
     "encoding/json"
     "errors"
     "fmt"
     "io"
+    "math/rand"
     "net/http"
     "os"
     "path/filepath"
     "regexp"
     "sort"
+    "strconv"
     "strings"
+    "sync"
     "time"
 )
 
@@ -26,16 +31,125 @@ type Config struct {
     Types         []string
     HTTPTimeout   time.Duration
     UserAgent     string
+
+    // Concurrency bounds how many hosts are fetched in parallel.
+    Concurrency int
+    // PerHostConcurrency bounds how many types are fetched in parallel for a single host.
+    PerHostConcurrency int
+    // RateLimit caps requests/sec issued to any single host (0 disables limiting).
+    RateLimit float64
+
+    // Dedup skips writing a new snapshot when its payload hashes identically to
+    // the previous one for the same (host, type). Enabled by default.
+    Dedup bool
+
+    // OutputSpecs are raw `-output type=...,dest=...` specs parsed into Sinks
+    // by FetchAll. When empty, FetchAll falls back to a single local sink
+    // rooted at OutputRoot, preserving the historical filesystem-only behavior.
+    OutputSpecs []string
+
+    // MaxRetries bounds attempts per request (1 means no retries). Retries
+    // apply to network errors and 429/5xx responses only.
+    MaxRetries int
+    // RetryBaseDelay is the backoff before the first retry; each subsequent
+    // retry doubles it, plus jitter (see backoff).
+    RetryBaseDelay time.Duration
+    // CircuitThreshold is the number of consecutive failed requests to a host
+    // (across its types) before that host's remaining types are skipped for
+    // the rest of the run (0 disables the breaker).
+    CircuitThreshold int
 }
 
 // DefaultConfig returns sensible defaults.
 func DefaultConfig() Config {
     return Config{
-        InstancesFile: filepath.Join("_config", "instance.txt"),
-        OutputRoot:    ".",
-        Types:         append([]string{}, Types...),
-        HTTPTimeout:   20 * time.Second,
-        UserAgent:     "neodb-trending-history-bot",
+        InstancesFile:      filepath.Join("_config", "instance.txt"),
+        OutputRoot:         ".",
+        Types:              append([]string{}, Types...),
+        HTTPTimeout:        20 * time.Second,
+        UserAgent:          "neodb-trending-history-bot",
+        Concurrency:        4,
+        PerHostConcurrency: 3,
+        RateLimit:          2.0,
+        Dedup:              true,
+        MaxRetries:         4,
+        RetryBaseDelay:     500 * time.Millisecond,
+        CircuitThreshold:   3,
+    }
+}
+
+// hostLimiter throttles requests to a single host to RateLimit requests/sec.
+// A nil *hostLimiter (RateLimit <= 0) never blocks.
+type hostLimiter struct {
+    ticker *time.Ticker
+}
+
+func newHostLimiter(rps float64) *hostLimiter {
+    if rps <= 0 {
+        return nil
+    }
+    return &hostLimiter{ticker: time.NewTicker(time.Duration(float64(time.Second) / rps))}
+}
+
+func (l *hostLimiter) wait(ctx context.Context) error {
+    if l == nil {
+        return nil
+    }
+    select {
+    case <-l.ticker.C:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func (l *hostLimiter) stop() {
+    if l != nil {
+        l.ticker.Stop()
+    }
+}
+
+// circuitBreaker opens after a run of consecutive request failures for a
+// host, so a dead instance doesn't eat the full retry budget for every
+// remaining type. A nil *circuitBreaker (CircuitThreshold <= 0) never opens.
+type circuitBreaker struct {
+    mu          sync.Mutex
+    threshold   int
+    consecutive int
+    open        bool
+}
+
+func newCircuitBreaker(threshold int) *circuitBreaker {
+    if threshold <= 0 {
+        return nil
+    }
+    return &circuitBreaker{threshold: threshold}
+}
+
+func (cb *circuitBreaker) isOpen() bool {
+    if cb == nil {
+        return false
+    }
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+    return cb.open
+}
+
+// recordResult updates the consecutive-failure streak; success resets it,
+// failure opens the breaker once the streak reaches threshold.
+func (cb *circuitBreaker) recordResult(success bool) {
+    if cb == nil {
+        return
+    }
+    cb.mu.Lock()
+    defer cb.mu.Unlock()
+    if success {
+        cb.consecutive = 0
+        return
+    }
+    cb.consecutive++
+    if cb.consecutive >= cb.threshold {
+        cb.open = true
     }
 }
 
@@ -103,7 +217,11 @@ func readInstances(path string) ([]string, error) {
 }
 
 // FetchAll fetches trending data for each instance and type, writing JSON files.
-func FetchAll(cfg Config, logf func(format string, args ...any)) error {
+// Hosts are fanned out over a bounded worker pool (Config.Concurrency); within a
+// host, types are fetched over a second bounded pool (Config.PerHostConcurrency)
+// so a single slow or dead instance can't stall the rest of the run. ctx governs
+// cancellation and deadlines for every in-flight HTTP request.
+func FetchAll(ctx context.Context, cfg Config, logf func(format string, args ...any)) error {
     if logf == nil {
         logf = func(string, ...any) {}
     }
@@ -116,87 +234,407 @@ func FetchAll(cfg Config, logf func(format string, args ...any)) error {
         return nil
     }
 
+    specs := cfg.OutputSpecs
+    if len(specs) == 0 {
+        specs = []string{"type=local,dest=" + cfg.OutputRoot}
+    }
+    sinks, err := BuildSinks(ctx, specs)
+    if err != nil {
+        return fmt.Errorf("build output sinks: %w", err)
+    }
+    defer func() {
+        for _, sink := range sinks {
+            if closeErr := sink.Close(); closeErr != nil {
+                logf("ERR close sink: %v", closeErr)
+            }
+        }
+    }()
+
     client := &http.Client{Timeout: cfg.HTTPTimeout}
-    now := time.Now().UTC()
-    y, m, d := now.Date()
-    ts := now.Format(time.RFC3339Nano)
+    ts := time.Now().UTC().Format(time.RFC3339Nano)
+
+    hostConcurrency := cfg.Concurrency
+    if hostConcurrency <= 0 {
+        hostConcurrency = 1
+    }
+    hostSem := make(chan struct{}, hostConcurrency)
 
+    var wg sync.WaitGroup
     for _, host := range hosts {
-        dash := dashifyHost(host)
-        // Collect per-type raw payloads and simplified entries for summary/README.
-        typePayloads := make(map[string]json.RawMessage, len(cfg.Types))
-        typeEntries := make(map[string][]entry, len(cfg.Types))
-        for _, t := range cfg.Types {
-            url := fmt.Sprintf("https://%s/api/trending/%s/", host, t)
-            req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
-            if err != nil {
-                logf("ERR build request %s %s: %v", host, t, err)
-                continue
-            }
-            req.Header.Set("Accept", "application/json")
-            if cfg.UserAgent != "" {
-                req.Header.Set("User-Agent", cfg.UserAgent)
-            }
+        host := host
+        wg.Add(1)
+        hostSem <- struct{}{}
+        go func() {
+            defer wg.Done()
+            defer func() { <-hostSem }()
+            fetchHost(ctx, cfg, client, sinks, host, ts, logf)
+        }()
+    }
+    wg.Wait()
 
-            resp, err := client.Do(req)
-            if err != nil {
-                logf("ERR fetch %s %s: %v", host, t, err)
-                continue
-            }
-            func() {
-                defer resp.Body.Close()
-                if resp.StatusCode != http.StatusOK {
-                    logf("WARN non-200 %s %s: %s", host, t, resp.Status)
-                    return
-                }
+    return nil
+}
 
-                // Limit read to 10MB to avoid bad responses.
-                const maxBytes = 10 << 20
-                r := io.LimitReader(resp.Body, maxBytes)
-                data, err := io.ReadAll(r)
-                if err != nil {
-                    logf("ERR read body %s %s: %v", host, t, err)
-                    return
-                }
+// typeResult is funneled back from a host's per-type workers so the caller sees
+// a stable, per-host batch before writing the summary JSON and README row.
+// skipped marks a dedup hit: entries are still populated (from the unchanged
+// snapshot) for the README, but payload is left nil so no summary entry or new
+// file is recorded for it.
+type typeResult struct {
+    typ     string
+    payload json.RawMessage
+    entries []entry
+    skipped bool
+}
 
-                dir := filepath.Join(cfg.OutputRoot, dash, fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", int(m)), fmt.Sprintf("%02d", d))
-                if mkErr := os.MkdirAll(dir, 0o755); mkErr != nil {
-                    logf("ERR mkdir %s: %v", dir, mkErr)
-                    return
-                }
-                fname := fmt.Sprintf("%s-%s-%s.json", ts, dash, t)
-                fpath := filepath.Join(dir, fname)
-                if writeErr := os.WriteFile(fpath, data, 0o644); writeErr != nil {
-                    logf("ERR write %s: %v", fpath, writeErr)
-                    return
-                }
-                logf("OK  saved %s", fpath)
+// indexEntry records the last snapshot written for a given (host, type) so
+// FetchAll can detect an unchanged payload without re-walking the tree. There
+// is no single LastPath once snapshots may fan out to several Sinks, so only
+// the hash and timestamp are tracked.
+type indexEntry struct {
+    LastHash string `json:"last_hash"`
+    LastTS   string `json:"last_ts"`
+}
 
-                // Save for summary and README.
-                typePayloads[t] = json.RawMessage(data)
-                ents := toEntries(data, host, t)
-                if len(ents) > 0 {
-                    typeEntries[t] = ents
-                }
-            }()
+func indexPath(root, dash string) string {
+    return filepath.Join(root, dash, "_index.json")
+}
+
+// readIndex loads a host's _index.json, returning an empty map if it doesn't exist yet.
+func readIndex(root, dash string) (map[string]indexEntry, error) {
+    data, err := os.ReadFile(indexPath(root, dash))
+    if errors.Is(err, os.ErrNotExist) {
+        return map[string]indexEntry{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    idx := map[string]indexEntry{}
+    if err := json.Unmarshal(data, &idx); err != nil {
+        return nil, err
+    }
+    return idx, nil
+}
+
+func writeIndex(root, dash string, idx map[string]indexEntry) error {
+    data, err := json.MarshalIndent(idx, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(indexPath(root, dash), data, 0o644)
+}
+
+// canonicalHash re-serializes a JSON payload with sorted keys (encoding/json
+// already sorts map keys) so unrelated key reordering upstream never produces
+// a false diff, then returns its SHA-256 hex digest.
+func canonicalHash(data []byte) (string, error) {
+    var v any
+    if err := json.Unmarshal(data, &v); err != nil {
+        return "", err
+    }
+    canon, err := json.Marshal(v)
+    if err != nil {
+        return "", err
+    }
+    sum := sha256.Sum256(canon)
+    return hex.EncodeToString(sum[:]), nil
+}
+
+// hostMetaPath is the small file recording a host directory's original,
+// non-dashified hostname, so later readers (Server) can rebuild absolute
+// URLs without guessing at the dashifyHost transform.
+func hostMetaPath(root, dash string) string {
+    return filepath.Join(root, dash, "_host.json")
+}
+
+type hostMeta struct {
+    Host string `json:"host"`
+}
+
+// writeHostMeta records host's raw form alongside its dashified directory.
+// Always on the local filesystem under root, like readIndex/writeIndex.
+func writeHostMeta(root, dash, host string) error {
+    if err := os.MkdirAll(filepath.Join(root, dash), 0o755); err != nil {
+        return err
+    }
+    data, err := json.Marshal(hostMeta{Host: host})
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(hostMetaPath(root, dash), data, 0o644)
+}
+
+// readRawHost returns the original hostname recorded for a dashified host
+// directory, falling back to dash itself if no metadata was ever written
+// (e.g. a tree from before this file existed).
+func readRawHost(root, dash string) string {
+    data, err := os.ReadFile(hostMetaPath(root, dash))
+    if err != nil {
+        return dash
+    }
+    var m hostMeta
+    if err := json.Unmarshal(data, &m); err != nil || m.Host == "" {
+        return dash
+    }
+    return m.Host
+}
+
+// fetchHost fetches every configured type for a single host, then writes the
+// summary JSON and README section, through every configured Sink, once all
+// types have reported back.
+func fetchHost(ctx context.Context, cfg Config, client *http.Client, sinks []Sink, host string, ts string, logf func(format string, args ...any)) {
+    dash := dashifyHost(host)
+
+    if err := writeHostMeta(cfg.OutputRoot, dash, host); err != nil {
+        logf("ERR write host meta for %s: %v", host, err)
+    }
+
+    idx, err := readIndex(cfg.OutputRoot, dash)
+    if err != nil {
+        logf("ERR read index for %s: %v", host, err)
+        idx = map[string]indexEntry{}
+    }
+    var idxMu sync.Mutex
+
+    typeConcurrency := cfg.PerHostConcurrency
+    if typeConcurrency <= 0 {
+        typeConcurrency = 1
+    }
+    limiter := newHostLimiter(cfg.RateLimit)
+    defer limiter.stop()
+    breaker := newCircuitBreaker(cfg.CircuitThreshold)
+
+    results := make(chan typeResult, len(cfg.Types))
+    typeSem := make(chan struct{}, typeConcurrency)
+    var wg sync.WaitGroup
+    for _, t := range cfg.Types {
+        if ctx.Err() != nil {
+            break
+        }
+        if breaker.isOpen() {
+            logf("SKIP host circuit-open %s %s", host, t)
+            continue
+        }
+        t := t
+        wg.Add(1)
+        typeSem <- struct{}{}
+        go func() {
+            defer wg.Done()
+            defer func() { <-typeSem }()
+            if err := limiter.wait(ctx); err != nil {
+                logf("ERR rate limit wait %s %s: %v", host, t, err)
+                return
+            }
+            if res, ok := fetchType(ctx, cfg, client, sinks, host, t, ts, &idx, &idxMu, breaker, logf); ok {
+                results <- res
+            }
+        }()
+    }
+    go func() {
+        wg.Wait()
+        close(results)
+    }()
+
+    typePayloads := make(map[string]json.RawMessage, len(cfg.Types))
+    typeEntries := make(map[string][]entry, len(cfg.Types))
+    dirty := false
+    for res := range results {
+        if len(res.entries) > 0 {
+            typeEntries[res.typ] = res.entries
+        }
+        if res.skipped {
+            continue
+        }
+        typePayloads[res.typ] = res.payload
+        dirty = true
+    }
+
+    if dirty {
+        if err := writeIndex(cfg.OutputRoot, dash, idx); err != nil {
+            logf("ERR write index for %s: %v", host, err)
         }
+    }
 
-        // Write summary JSON without trailing type in filename.
-        if len(typePayloads) > 0 {
-            if err := writeSummaryJSON(cfg.OutputRoot, dash, y, int(m), d, ts, host, typePayloads); err != nil {
+    // Write summary JSON without trailing type in filename.
+    if len(typePayloads) > 0 {
+        for _, sink := range sinks {
+            if err := sink.WriteSummary(host, ts, typePayloads); err != nil {
                 logf("ERR write summary for %s: %v", host, err)
             }
         }
+    }
 
-        // Append README section with a table snapshot.
-        if len(typeEntries) > 0 {
-            if err := appendREADME(cfg.OutputRoot, dash, host, y, int(m), d, ts, typeEntries); err != nil {
+    // Append README section with a table snapshot.
+    if len(typeEntries) > 0 {
+        for _, sink := range sinks {
+            if err := sink.WriteREADME(host, ts, typeEntries); err != nil {
                 logf("ERR write README for %s: %v", host, err)
             }
         }
     }
+}
 
-    return nil
+// fetchScheme is the scheme used to build each type's request URL. It's a
+// var rather than a literal so tests can point FetchAll's internal client at
+// a plain httptest.Server instead of standing up trust for a self-signed
+// TLS certificate.
+var fetchScheme = "https"
+
+// fetchType fetches and saves a single (host, type) trending payload to every
+// configured Sink. The bool return reports whether a result was produced
+// (false on any error or non-200). When cfg.Dedup is set and the payload
+// hashes identically to idx[t].LastHash, no new file/summary entry is written,
+// but README entries for the unchanged snapshot are still returned so the
+// day's digest stays complete.
+func fetchType(ctx context.Context, cfg Config, client *http.Client, sinks []Sink, host, t, ts string, idx *map[string]indexEntry, idxMu *sync.Mutex, breaker *circuitBreaker, logf func(format string, args ...any)) (typeResult, bool) {
+    url := fmt.Sprintf("%s://%s/api/trending/%s/", fetchScheme, host, t)
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        logf("ERR build request %s %s: %v", host, t, err)
+        return typeResult{}, false
+    }
+    req.Header.Set("Accept", "application/json")
+    if cfg.UserAgent != "" {
+        req.Header.Set("User-Agent", cfg.UserAgent)
+    }
+
+    resp, err := fetchWithRetry(ctx, client, req, cfg, host, t, logf)
+    if err != nil {
+        // fetchWithRetry only returns an error for network failures or
+        // exhausted 429/5xx retries, so these are the breaker's retryable
+        // failure classes.
+        logf("ERR fetch %s %s: %v", host, t, err)
+        breaker.recordResult(false)
+        return typeResult{}, false
+    }
+    defer resp.Body.Close()
+    // Any other non-2xx (404, 403, ...) means the host responded, just not
+    // with this type; it doesn't count against the breaker, or a host that
+    // simply lacks one of the seven types would trip it for the rest.
+    breaker.recordResult(true)
+    if resp.StatusCode != http.StatusOK {
+        logf("WARN non-200 %s %s: %s", host, t, resp.Status)
+        return typeResult{}, false
+    }
+
+    // Limit read to 10MB to avoid bad responses.
+    const maxBytes = 10 << 20
+    r := io.LimitReader(resp.Body, maxBytes)
+    data, err := io.ReadAll(r)
+    if err != nil {
+        logf("ERR read body %s %s: %v", host, t, err)
+        return typeResult{}, false
+    }
+
+    if cfg.Dedup {
+        hash, hashErr := canonicalHash(data)
+        if hashErr != nil {
+            logf("ERR hash payload %s %s: %v", host, t, hashErr)
+        } else {
+            idxMu.Lock()
+            prev, ok := (*idx)[t]
+            idxMu.Unlock()
+            if ok && prev.LastHash == hash {
+                logf("SKIP unchanged %s %s", host, t)
+                return typeResult{typ: t, entries: toEntries(data, host, t), skipped: true}, true
+            }
+        }
+    }
+
+    for _, sink := range sinks {
+        if writeErr := sink.WritePayload(host, t, ts, data); writeErr != nil {
+            logf("ERR write %s %s: %v", host, t, writeErr)
+            return typeResult{}, false
+        }
+    }
+    logf("OK  saved %s %s", host, t)
+
+    if cfg.Dedup {
+        if hash, hashErr := canonicalHash(data); hashErr == nil {
+            idxMu.Lock()
+            (*idx)[t] = indexEntry{LastHash: hash, LastTS: ts}
+            idxMu.Unlock()
+        }
+    }
+
+    return typeResult{typ: t, payload: json.RawMessage(data), entries: toEntries(data, host, t)}, true
+}
+
+// fetchWithRetry issues req, retrying on network errors and 429/5xx responses
+// up to cfg.MaxRetries attempts total. Retries back off exponentially from
+// cfg.RetryBaseDelay with jitter (see backoff), except when a 429 carries a
+// Retry-After header, which takes precedence. req is reused across attempts;
+// safe because it carries no body. The returned response (on success) is left
+// unread and unclosed for the caller to consume.
+func fetchWithRetry(ctx context.Context, client *http.Client, req *http.Request, cfg Config, host, t string, logf func(format string, args ...any)) (*http.Response, error) {
+    maxAttempts := cfg.MaxRetries
+    if maxAttempts <= 0 {
+        maxAttempts = 1
+    }
+    base := cfg.RetryBaseDelay
+    if base <= 0 {
+        base = 500 * time.Millisecond
+    }
+
+    var lastErr error
+    var retryAfter time.Duration
+    for attempt := 0; attempt < maxAttempts; attempt++ {
+        if attempt > 0 {
+            delay := retryAfter
+            if delay <= 0 {
+                delay = backoff(base, attempt)
+            }
+            logf("RETRY %s %s attempt %d/%d in %s: %v", host, t, attempt+1, maxAttempts, delay.Truncate(time.Millisecond), lastErr)
+            select {
+            case <-time.After(delay):
+            case <-ctx.Done():
+                return nil, ctx.Err()
+            }
+            retryAfter = 0
+        }
+
+        resp, err := client.Do(req)
+        if err != nil {
+            lastErr = err
+            continue
+        }
+        if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+            lastErr = fmt.Errorf("status %s", resp.Status)
+            retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+            resp.Body.Close()
+            continue
+        }
+        return resp, nil
+    }
+    return nil, lastErr
+}
+
+// backoff returns base * 2^(attempt-1) with ±20% jitter, so concurrent
+// retries against the same host don't all land on the same tick.
+func backoff(base time.Duration, attempt int) time.Duration {
+    d := base * time.Duration(int64(1)<<uint(attempt-1))
+    jitter := time.Duration((rand.Float64()*0.4 - 0.2) * float64(d))
+    return d + jitter
+}
+
+// parseRetryAfter parses a Retry-After header value, either delay-seconds or
+// an HTTP-date, returning 0 if absent, malformed, or already past.
+func parseRetryAfter(v string) time.Duration {
+    if v == "" {
+        return 0
+    }
+    if secs, err := strconv.Atoi(v); err == nil {
+        if secs < 0 {
+            return 0
+        }
+        return time.Duration(secs) * time.Second
+    }
+    if t, err := http.ParseTime(v); err == nil {
+        if d := time.Until(t); d > 0 {
+            return d
+        }
+    }
+    return 0
 }
 
 // entry is a simplified view for README rendering.
@@ -204,6 +642,9 @@ type entry struct {
     Title string
     Image string
     Link  string
+    // ID is a stable identifier for this item across snapshots, used to key
+    // rank-over-time series (see BuildTimeline). Empty if none could be found.
+    ID string
 }
 
 // toEntries attempts to parse trending payload into a list of entries.
@@ -257,7 +698,7 @@ func toEntries(data []byte, host string, typ string) []entry {
             if title == "" && img == "" && link == "" {
                 continue
             }
-            out = append(out, entry{Title: title, Image: img, Link: link})
+            out = append(out, entry{Title: title, Image: img, Link: link, ID: extractItemID(m)})
         }
     }
     return out
@@ -359,81 +800,24 @@ func pickLink(m map[string]any, host, typ string) string {
     return ""
 }
 
-func writeSummaryJSON(root, dash string, y int, m int, d int, ts string, host string, payloads map[string]json.RawMessage) error {
-    dir := filepath.Join(root, dash, fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", m), fmt.Sprintf("%02d", d))
-    if err := os.MkdirAll(dir, 0o755); err != nil {
-        return err
-    }
-    fname := fmt.Sprintf("%s-%s.json", ts, dash)
-    fpath := filepath.Join(dir, fname)
-    obj := map[string]any{
-        "timestamp": ts,
-        "host":      host,
-        "types":     payloads,
-    }
-    data, err := json.MarshalIndent(obj, "", "  ")
-    if err != nil {
-        return err
-    }
-    return os.WriteFile(fpath, data, 0o644)
-}
-
-func appendREADME(root, dash, host string, y int, m int, d int, ts string, typeEntries map[string][]entry) error {
-    dir := filepath.Join(root, dash, fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", m), fmt.Sprintf("%02d", d))
-    if err := os.MkdirAll(dir, 0o755); err != nil {
-        return err
-    }
-    fpath := filepath.Join(dir, "README.md")
-
-    var b strings.Builder
-    if _, err := os.Stat(fpath); errors.Is(err, os.ErrNotExist) {
-        // New file: add top-level title
-        b.WriteString(fmt.Sprintf("# NeoDB Trending History for %s\n\n", host))
-    }
-    b.WriteString(fmt.Sprintf("## %s\n", ts))
-    // Build a wide table: first column is row label, remaining 19 cells for items
-    itemCols := 19
-    totalCols := 1 + itemCols
-    // header row with blanks
-    b.WriteString("|")
-    for i := 0; i < totalCols; i++ {
-        b.WriteString("      |")
-    }
-    b.WriteString("\n|")
-    for i := 0; i < totalCols; i++ {
-        b.WriteString(" ---- |")
-    }
-    b.WriteString("\n")
-    // rows: include only types that were fetched (non-empty entries), keep stable order
-    for _, t := range Types {
-        ents, ok := typeEntries[t]
-        if !ok || len(ents) == 0 {
-            continue
-        }
-        label := typeLabel(t)
-        cells := renderCells(ents, itemCols, host, t)
-        // write row label + cells
-        b.WriteString("|")
-        b.WriteString(" ")
-        b.WriteString(escapePipes(label))
-        b.WriteString(" |")
-        for _, c := range cells {
-            b.WriteString(" ")
-            b.WriteString(c)
-            b.WriteString(" |")
+// extractItemID picks a stable identifier for an item, independent of host or
+// type, so the same item can be tracked across snapshots even if its URL
+// changes. Unlike pickLink, it never falls back to constructing a URL: it
+// just returns whichever raw field identifies the item, trying subject.id,
+// then url, then id, in that order.
+func extractItemID(m map[string]any) string {
+    if sub, ok := m["subject"].(map[string]any); ok {
+        if v, ok := sub["id"].(string); ok && v != "" {
+            return v
         }
-        b.WriteString("\n")
     }
-    b.WriteString("\n")
-
-    // Append to file
-    f, err := os.OpenFile(fpath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-    if err != nil {
-        return err
+    if v, ok := m["url"].(string); ok && v != "" {
+        return v
     }
-    defer f.Close()
-    _, err = f.WriteString(b.String())
-    return err
+    if v, ok := m["id"].(string); ok && v != "" {
+        return v
+    }
+    return ""
 }
 
 func renderCells(ents []entry, columns int, host, typ string) []string {
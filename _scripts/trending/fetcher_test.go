@@ -0,0 +1,85 @@
+package trending
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+)
+
+func TestCanonicalHash(t *testing.T) {
+    cases := []struct {
+        name     string
+        a, b     string
+        wantSame bool
+    }{
+        {"key order doesn't matter", `{"a":1,"b":2}`, `{"b":2,"a":1}`, true},
+        {"whitespace doesn't matter", `{"a":1}`, "{\n  \"a\": 1\n}", true},
+        {"different values differ", `{"a":1}`, `{"a":2}`, false},
+        {"different keys differ", `{"a":1}`, `{"b":1}`, false},
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            ha, err := canonicalHash([]byte(c.a))
+            if err != nil {
+                t.Fatalf("hash a: %v", err)
+            }
+            hb, err := canonicalHash([]byte(c.b))
+            if err != nil {
+                t.Fatalf("hash b: %v", err)
+            }
+            if same := ha == hb; same != c.wantSame {
+                t.Errorf("canonicalHash(%q) == canonicalHash(%q): got %v, want %v", c.a, c.b, same, c.wantSame)
+            }
+        })
+    }
+}
+
+// TestFetchTypeDedupSkipsUnchangedPayload exercises the dedup check inline in
+// fetchType: an unchanged payload on a later fetch should be reported as
+// skipped (no new file/summary entry), while a changed payload should not.
+func TestFetchTypeDedupSkipsUnchangedPayload(t *testing.T) {
+    payload := `[{"title":"Alpha","url":"/items/1"}]`
+    changed := `[{"title":"Alpha"},{"title":"Beta","url":"/items/2"}]`
+    body := payload
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(body))
+    }))
+    defer srv.Close()
+
+    host := srv.Listener.Addr().String()
+    cfg := DefaultConfig()
+    cfg.Dedup = true
+    idx := map[string]indexEntry{}
+    var mu sync.Mutex
+    logf := func(string, ...any) {}
+
+    res, ok := fetchType(context.Background(), cfg, srv.Client(), nil, host, "book", "ts1", &idx, &mu, nil, logf)
+    if !ok {
+        t.Fatal("first fetch: want ok=true")
+    }
+    if res.skipped {
+        t.Fatal("first fetch: want skipped=false (no prior snapshot)")
+    }
+
+    res, ok = fetchType(context.Background(), cfg, srv.Client(), nil, host, "book", "ts2", &idx, &mu, nil, logf)
+    if !ok {
+        t.Fatal("second fetch (unchanged): want ok=true")
+    }
+    if !res.skipped {
+        t.Fatal("second fetch (unchanged): want skipped=true")
+    }
+    if len(res.entries) == 0 {
+        t.Error("second fetch (unchanged): want README entries even when skipped")
+    }
+
+    body = changed
+    res, ok = fetchType(context.Background(), cfg, srv.Client(), nil, host, "book", "ts3", &idx, &mu, nil, logf)
+    if !ok {
+        t.Fatal("third fetch (changed): want ok=true")
+    }
+    if res.skipped {
+        t.Fatal("third fetch (changed): want skipped=false")
+    }
+}
@@ -0,0 +1,112 @@
+package trending
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// ListHosts returns every dashified host directory under root.
+func ListHosts(root string) ([]string, error) {
+    entries, err := os.ReadDir(root)
+    if err != nil {
+        return nil, err
+    }
+    var hosts []string
+    for _, e := range entries {
+        if e.IsDir() && e.Name() == dashifyHost(e.Name()) {
+            hosts = append(hosts, e.Name())
+        }
+    }
+    return hosts, nil
+}
+
+// GenerateReport computes TopMovers for every type for a single host between
+// from and to, writes root/<host>/MOVERS.md, and appends a "Biggest movers"
+// section to that host's most recent daily README.md, next to its table.
+func GenerateReport(root, host string, types []string, from, to time.Time, limit int) error {
+    sections := make(map[string][]Mover, len(types))
+    for _, typ := range types {
+        movers, err := TopMovers(root, host, typ, from, to, limit)
+        if err != nil {
+            return fmt.Errorf("top movers %s %s: %w", host, typ, err)
+        }
+        if len(movers) > 0 {
+            sections[typ] = movers
+        }
+    }
+
+    body := renderMoversSection(host, from, to, types, sections)
+
+    if err := os.WriteFile(filepath.Join(root, host, "MOVERS.md"), []byte(body), 0o644); err != nil {
+        return fmt.Errorf("write MOVERS.md for %s: %w", host, err)
+    }
+
+    dates, err := walkDates(root, host)
+    if err != nil {
+        return fmt.Errorf("list dates for %s: %w", host, err)
+    }
+    if len(dates) == 0 {
+        return nil
+    }
+    latest := dates[len(dates)-1]
+    parts := strings.SplitN(latest, "-", 3)
+    readmePath := filepath.Join(root, host, parts[0], parts[1], parts[2], "README.md")
+    f, err := os.OpenFile(readmePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+    if err != nil {
+        return fmt.Errorf("append README for %s: %w", host, err)
+    }
+    defer f.Close()
+    _, err = f.WriteString(body)
+    return err
+}
+
+// renderMoversSection builds the "Biggest movers" Markdown section shared by
+// MOVERS.md and the per-day README append.
+func renderMoversSection(host string, from, to time.Time, types []string, sections map[string][]Mover) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "## \U0001F53A Biggest movers this week (%s → %s)\n\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+    any := false
+    for _, typ := range types {
+        movers, ok := sections[typ]
+        if !ok {
+            continue
+        }
+        any = true
+        fmt.Fprintf(&b, "### %s\n\n", typeLabel(typ))
+        b.WriteString("| item | old rank | new rank | change |\n")
+        b.WriteString("| ---- | -------- | -------- | ------ |\n")
+        for _, m := range movers {
+            b.WriteString("| " + escapePipes(m.Title) + " | " + rankCell(m.OldRank) + " | " + rankCell(m.NewRank) + " | " + deltaCell(m) + " |\n")
+        }
+        b.WriteString("\n")
+    }
+    if !any {
+        b.WriteString("No significant rank changes.\n\n")
+    }
+    return b.String()
+}
+
+func rankCell(rank int) string {
+    if rank == 0 {
+        return "—"
+    }
+    return fmt.Sprintf("#%d", rank)
+}
+
+func deltaCell(m Mover) string {
+    switch {
+    case m.OldRank == 0:
+        return "new"
+    case m.NewRank == 0:
+        return "dropped off"
+    case m.Delta > 0:
+        return fmt.Sprintf("↑%d", m.Delta)
+    case m.Delta < 0:
+        return fmt.Sprintf("↓%d", -m.Delta)
+    default:
+        return "—"
+    }
+}
@@ -0,0 +1,137 @@
+package trending
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestBackoffDoublesWithJitter(t *testing.T) {
+    base := 100 * time.Millisecond
+    for attempt := 1; attempt <= 4; attempt++ {
+        want := base * time.Duration(int64(1)<<uint(attempt-1))
+        lo, hi := want*8/10, want*12/10
+        for i := 0; i < 20; i++ {
+            got := backoff(base, attempt)
+            if got < lo || got > hi {
+                t.Fatalf("backoff(%s, %d) = %s, want within [%s, %s]", base, attempt, got, lo, hi)
+            }
+        }
+    }
+}
+
+func TestParseRetryAfter(t *testing.T) {
+    cases := []struct {
+        name    string
+        header  string
+        wantPos bool
+    }{
+        {"empty", "", false},
+        {"seconds", "5", true},
+        {"malformed", "not-a-date", false},
+        {"past HTTP-date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), false},
+        {"future HTTP-date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), true},
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            d := parseRetryAfter(c.header)
+            if (d > 0) != c.wantPos {
+                t.Errorf("parseRetryAfter(%q) = %s, want positive=%v", c.header, d, c.wantPos)
+            }
+        })
+    }
+}
+
+func TestFetchWithRetrySucceedsAfterTransientErrors(t *testing.T) {
+    var calls int
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        if calls < 3 {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        w.WriteHeader(http.StatusOK)
+    }))
+    defer srv.Close()
+
+    cfg := Config{MaxRetries: 4, RetryBaseDelay: 5 * time.Millisecond}
+    req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+    resp, err := fetchWithRetry(context.Background(), srv.Client(), req, cfg, "h", "t", func(string, ...any) {})
+    if err != nil {
+        t.Fatalf("fetchWithRetry: %v", err)
+    }
+    resp.Body.Close()
+    if calls != 3 {
+        t.Fatalf("want 3 calls, got %d", calls)
+    }
+}
+
+func TestFetchWithRetryExhausted(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusBadGateway)
+    }))
+    defer srv.Close()
+
+    cfg := Config{MaxRetries: 3, RetryBaseDelay: 2 * time.Millisecond}
+    req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+    _, err := fetchWithRetry(context.Background(), srv.Client(), req, cfg, "h", "t", func(string, ...any) {})
+    if err == nil {
+        t.Fatal("want error after exhausting retries")
+    }
+}
+
+func TestFetchWithRetryDoesNotRetryNon200NonRetryable(t *testing.T) {
+    var calls int
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        w.WriteHeader(http.StatusNotFound)
+    }))
+    defer srv.Close()
+
+    cfg := Config{MaxRetries: 4, RetryBaseDelay: 2 * time.Millisecond}
+    req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+    resp, err := fetchWithRetry(context.Background(), srv.Client(), req, cfg, "h", "t", func(string, ...any) {})
+    if err != nil {
+        t.Fatalf("fetchWithRetry: %v", err)
+    }
+    resp.Body.Close()
+    if calls != 1 {
+        t.Fatalf("want 1 call (404 isn't retryable), got %d", calls)
+    }
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+    cb := newCircuitBreaker(3)
+    for i := 0; i < 2; i++ {
+        cb.recordResult(false)
+        if cb.isOpen() {
+            t.Fatalf("should not be open after %d failures", i+1)
+        }
+    }
+    cb.recordResult(false)
+    if !cb.isOpen() {
+        t.Fatal("should be open after 3 consecutive failures")
+    }
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+    cb := newCircuitBreaker(2)
+    cb.recordResult(false)
+    cb.recordResult(true)
+    cb.recordResult(false)
+    if cb.isOpen() {
+        t.Fatal("should not be open: success reset the streak")
+    }
+}
+
+func TestCircuitBreakerDisabledWhenThresholdZero(t *testing.T) {
+    var cb *circuitBreaker
+    for i := 0; i < 100; i++ {
+        cb.recordResult(false)
+    }
+    if cb.isOpen() {
+        t.Fatal("nil breaker (threshold <= 0) should never open")
+    }
+}
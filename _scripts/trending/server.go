@@ -0,0 +1,312 @@
+package trending
+
+import (
+    "encoding/json"
+    "fmt"
+    "html"
+    "net/http"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Server browses a tree of snapshots previously written by FetchAll (via
+// localSink) as a small JSON API plus a minimal HTML view, in the spirit of
+// Caddy's `browse` middleware: no database, just the directory layout.
+type Server struct {
+    Root string
+}
+
+// NewServer returns a Server rooted at an existing OutputRoot directory.
+func NewServer(root string) *Server {
+    return &Server{Root: root}
+}
+
+// Handler returns the http.Handler serving the API and browsable UI.
+func (s *Server) Handler() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/", s.handleIndex)
+    mux.HandleFunc("/api/hosts", s.handleListHosts)
+    mux.HandleFunc("/api/hosts/", s.handleHostRoute)
+    mux.HandleFunc("/raw/", s.handleRaw)
+    return mux
+}
+
+// ListenAndServe is a convenience wrapper around http.ListenAndServe(addr, s.Handler()).
+func (s *Server) ListenAndServe(addr string) error {
+    return http.ListenAndServe(addr, s.Handler())
+}
+
+// hostSummary is the /api/hosts list item: a dashified host with its date
+// range. RawHost is the original hostname (e.g. "neodb.social"), recovered
+// from the per-host metadata fetchHost writes, for building absolute URLs;
+// it falls back to Host for trees written before that metadata existed.
+type hostSummary struct {
+    Host     string `json:"host"`
+    RawHost  string `json:"raw_host"`
+    Earliest string `json:"earliest,omitempty"`
+    Latest   string `json:"latest,omitempty"`
+}
+
+// listHosts returns every dashified host directory under Root, sorted by name.
+func (s *Server) listHosts() ([]hostSummary, error) {
+    dirs, err := ListHosts(s.Root)
+    if err != nil {
+        return nil, err
+    }
+    var hosts []hostSummary
+    for _, host := range dirs {
+        dates, err := s.listDates(host)
+        if err != nil || len(dates) == 0 {
+            continue
+        }
+        hosts = append(hosts, hostSummary{Host: host, RawHost: readRawHost(s.Root, host), Earliest: dates[0], Latest: dates[len(dates)-1]})
+    }
+    sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+    return hosts, nil
+}
+
+// listDates returns every YYYY-MM-DD directory under a host, ascending, that
+// contains at least one snapshot file.
+func (s *Server) listDates(host string) ([]string, error) {
+    return walkDates(s.Root, host)
+}
+
+// snapshotFiles returns the per-type JSON payload files for (host, typ) on a
+// given YYYY-MM-DD date, sorted by timestamp (filename order).
+func (s *Server) snapshotFiles(host, typ, date string) ([]string, error) {
+    return walkSnapshotFiles(s.Root, host, typ, date)
+}
+
+func (s *Server) handleListHosts(w http.ResponseWriter, r *http.Request) {
+    hosts, err := s.listHosts()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    writeJSON(w, hosts)
+}
+
+// handleHostRoute dispatches /api/hosts/{host}/types/{type} and
+// /api/hosts/{host}/timeline/{type}.
+func (s *Server) handleHostRoute(w http.ResponseWriter, r *http.Request) {
+    rest := strings.TrimPrefix(r.URL.Path, "/api/hosts/")
+    parts := strings.Split(rest, "/")
+    if len(parts) != 3 {
+        http.NotFound(w, r)
+        return
+    }
+    host, section, typ := parts[0], parts[1], parts[2]
+    switch section {
+    case "types":
+        s.handleTypes(w, r, host, typ)
+    case "timeline":
+        s.handleTimeline(w, r, host, typ)
+    default:
+        http.NotFound(w, r)
+    }
+}
+
+// snapshotEntries is one dated entry in the /api/hosts/{host}/types/{type} response.
+type snapshotEntries struct {
+    TS      string  `json:"ts"`
+    Entries []entry `json:"entries"`
+}
+
+func (s *Server) handleTypes(w http.ResponseWriter, r *http.Request, host, typ string) {
+    from := r.URL.Query().Get("from")
+    to := r.URL.Query().Get("to")
+    dates, err := s.listDates(host)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    rawHost := readRawHost(s.Root, host)
+    var out []snapshotEntries
+    for _, date := range dates {
+        if from != "" && date < from {
+            continue
+        }
+        if to != "" && date > to {
+            continue
+        }
+        files, err := s.snapshotFiles(host, typ, date)
+        if err != nil {
+            continue
+        }
+        for _, fpath := range files {
+            data, err := os.ReadFile(fpath)
+            if err != nil {
+                continue
+            }
+            ts := strings.TrimSuffix(filepath.Base(fpath), fmt.Sprintf("-%s-%s.json", host, typ))
+            out = append(out, snapshotEntries{TS: ts, Entries: toEntries(data, rawHost, typ)})
+        }
+    }
+    writeJSON(w, out)
+}
+
+// timelineItem is the per-item series returned by /api/hosts/{host}/timeline/{type}.
+type timelineItem struct {
+    ID     string      `json:"id"`
+    Title  string      `json:"title"`
+    Points []RankPoint `json:"points"`
+}
+
+func (s *Server) handleTimeline(w http.ResponseWriter, r *http.Request, host, typ string) {
+    series, err := BuildTimeline(s.Root, host, typ, time.Time{}, time.Now())
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    titles, _ := snapshotNear(s.Root, host, typ, time.Now())
+    titleByID := make(map[string]string, len(titles))
+    for _, e := range titles {
+        titleByID[e.ID] = e.Title
+    }
+
+    ids := make([]string, 0, len(series))
+    for id := range series {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+
+    out := make([]timelineItem, 0, len(ids))
+    for _, id := range ids {
+        out = append(out, timelineItem{ID: id, Title: titleByID[id], Points: series[id]})
+    }
+    writeJSON(w, out)
+}
+
+// handleRaw serves a snapshot file directly: /raw/{host}/{yyyy}/{mm}/{dd}/{file}.json.
+func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request) {
+    rest := strings.TrimPrefix(r.URL.Path, "/raw/")
+    if rest == "" || strings.Contains(rest, "..") {
+        http.NotFound(w, r)
+        return
+    }
+    http.ServeFile(w, r, filepath.Join(s.Root, filepath.FromSlash(rest)))
+}
+
+// handleIndex renders the same wide table renderREADMESection writes into each
+// day's README.md, for any host
+// and date selected via query params, so the archive is browsable without
+// cloning it locally.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path != "/" {
+        http.NotFound(w, r)
+        return
+    }
+    hosts, err := s.listHosts()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if len(hosts) == 0 {
+        fmt.Fprint(w, "<p>no snapshots yet</p>")
+        return
+    }
+    host := r.URL.Query().Get("host")
+    if host == "" {
+        host = hosts[0].Host
+    }
+    date := r.URL.Query().Get("date")
+    if date == "" {
+        for _, h := range hosts {
+            if h.Host == host {
+                date = h.Latest
+            }
+        }
+    }
+
+    rawHost := host
+    for _, h := range hosts {
+        if h.Host == host {
+            rawHost = h.RawHost
+        }
+    }
+
+    typeEntries := map[string][]entry{}
+    var ts string
+    for _, t := range Types {
+        files, err := s.snapshotFiles(host, t, date)
+        if err != nil || len(files) == 0 {
+            continue
+        }
+        fpath := files[len(files)-1]
+        data, err := os.ReadFile(fpath)
+        if err != nil {
+            continue
+        }
+        if ents := toEntries(data, rawHost, t); len(ents) > 0 {
+            typeEntries[t] = ents
+        }
+        ts = strings.TrimSuffix(filepath.Base(fpath), fmt.Sprintf("-%s-%s.json", host, t))
+    }
+
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    fmt.Fprintf(w, "<h1>NeoDB Trending History</h1>\n<form>\n<select name=\"host\" onchange=\"this.form.submit()\">\n")
+    for _, h := range hosts {
+        selected := ""
+        if h.Host == host {
+            selected = " selected"
+        }
+        fmt.Fprintf(w, "<option value=\"%s\"%s>%s</option>\n", html.EscapeString(h.Host), selected, html.EscapeString(h.Host))
+    }
+    fmt.Fprintf(w, "</select>\n<input type=\"text\" name=\"date\" value=\"%s\">\n<button type=\"submit\">go</button>\n</form>\n", html.EscapeString(date))
+    if ts == "" {
+        fmt.Fprint(w, "<p>no snapshot for that date</p>")
+        return
+    }
+    fmt.Fprint(w, markdownTableToHTML(renderREADMESection(rawHost, ts, typeEntries)))
+}
+
+// markdownTableToHTML renders a GitHub-flavored Markdown pipe table as an
+// HTML table; good enough for the browsable index, which only ever feeds it
+// output from renderREADMESection.
+func markdownTableToHTML(md string) string {
+    var b strings.Builder
+    lines := strings.Split(strings.TrimSpace(md), "\n")
+    inTable := false
+    for _, line := range lines {
+        line = strings.TrimSpace(line)
+        if !strings.HasPrefix(line, "|") {
+            if inTable {
+                b.WriteString("</table>\n")
+                inTable = false
+            }
+            if strings.HasPrefix(line, "## ") {
+                fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(strings.TrimPrefix(line, "## ")))
+            }
+            continue
+        }
+        if strings.Contains(line, "----") && strings.Trim(line, "|- ") == "" {
+            continue // skip the header separator row
+        }
+        cells := strings.Split(strings.Trim(line, "|"), "|")
+        if !inTable {
+            b.WriteString("<table border=\"1\" cellspacing=\"0\" cellpadding=\"4\">\n")
+            inTable = true
+        }
+        b.WriteString("<tr>")
+        for _, c := range cells {
+            b.WriteString("<td>" + strings.TrimSpace(c) + "</td>")
+        }
+        b.WriteString("</tr>\n")
+    }
+    if inTable {
+        b.WriteString("</table>\n")
+    }
+    return b.String()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+    w.Header().Set("Content-Type", "application/json")
+    enc := json.NewEncoder(w)
+    enc.SetIndent("", "  ")
+    if err := enc.Encode(v); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
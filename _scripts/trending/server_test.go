@@ -0,0 +1,158 @@
+package trending
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "path/filepath"
+    "testing"
+)
+
+// newTestServerFixture writes one host's worth of snapshots (one type,
+// two dates) under a temp root and records its raw hostname, mirroring what
+// fetchHost/localSink produce, so Server's handlers have something to read.
+func newTestServerFixture(t *testing.T) (root, dash, rawHost string) {
+    t.Helper()
+    root = t.TempDir()
+    rawHost = "example.test"
+    dash = dashifyHost(rawHost)
+
+    writeTestSnapshot(t, root, dash, "book", "2026-07-18", "2026-07-18T00:00:00Z", [][2]string{
+        {"Alpha", "/items/alpha"},
+    })
+    writeTestSnapshot(t, root, dash, "book", "2026-07-25", "2026-07-25T00:00:00Z", [][2]string{
+        {"Alpha", "/items/alpha"},
+        {"Beta", "/items/beta"},
+    })
+    if err := writeHostMeta(root, dash, rawHost); err != nil {
+        t.Fatalf("writeHostMeta: %v", err)
+    }
+    return root, dash, rawHost
+}
+
+func TestHandleListHosts(t *testing.T) {
+    root, dash, rawHost := newTestServerFixture(t)
+    srv := httptest.NewServer(NewServer(root).Handler())
+    defer srv.Close()
+
+    resp, err := http.Get(srv.URL + "/api/hosts")
+    if err != nil {
+        t.Fatalf("GET /api/hosts: %v", err)
+    }
+    defer resp.Body.Close()
+    var hosts []hostSummary
+    if err := json.NewDecoder(resp.Body).Decode(&hosts); err != nil {
+        t.Fatalf("decode: %v", err)
+    }
+    if len(hosts) != 1 || hosts[0].Host != dash || hosts[0].RawHost != rawHost {
+        t.Fatalf("want one host {Host:%q RawHost:%q}, got %+v", dash, rawHost, hosts)
+    }
+    if hosts[0].Earliest != "2026-07-18" || hosts[0].Latest != "2026-07-25" {
+        t.Errorf("want date range 2026-07-18..2026-07-25, got %+v", hosts[0])
+    }
+}
+
+func TestHandleTypesUsesRawHostInLinks(t *testing.T) {
+    root, dash, rawHost := newTestServerFixture(t)
+    srv := httptest.NewServer(NewServer(root).Handler())
+    defer srv.Close()
+
+    resp, err := http.Get(srv.URL + "/api/hosts/" + dash + "/types/book")
+    if err != nil {
+        t.Fatalf("GET types: %v", err)
+    }
+    defer resp.Body.Close()
+    var out []snapshotEntries
+    if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+        t.Fatalf("decode: %v", err)
+    }
+    if len(out) != 2 {
+        t.Fatalf("want 2 dated snapshots, got %d: %+v", len(out), out)
+    }
+    wantLink := "https://" + rawHost + "/items/alpha"
+    if out[0].Entries[0].Link != wantLink {
+        t.Errorf("want link %q built from raw host, got %q", wantLink, out[0].Entries[0].Link)
+    }
+}
+
+func TestHandleTimeline(t *testing.T) {
+    root, dash, _ := newTestServerFixture(t)
+    srv := httptest.NewServer(NewServer(root).Handler())
+    defer srv.Close()
+
+    resp, err := http.Get(srv.URL + "/api/hosts/" + dash + "/timeline/book")
+    if err != nil {
+        t.Fatalf("GET timeline: %v", err)
+    }
+    defer resp.Body.Close()
+    var items []timelineItem
+    if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+        t.Fatalf("decode: %v", err)
+    }
+    byID := map[string]timelineItem{}
+    for _, it := range items {
+        byID[it.ID] = it
+    }
+    alpha, ok := byID["/items/alpha"]
+    if !ok || len(alpha.Points) != 2 {
+        t.Fatalf("want alpha present in both snapshots, got %+v", byID)
+    }
+    beta, ok := byID["/items/beta"]
+    if !ok || len(beta.Points) != 1 {
+        t.Fatalf("want beta present in only the later snapshot, got %+v", byID)
+    }
+}
+
+func TestHandleHostRouteRejectsBadSegmentCounts(t *testing.T) {
+    root, dash, _ := newTestServerFixture(t)
+    srv := httptest.NewServer(NewServer(root).Handler())
+    defer srv.Close()
+
+    cases := []string{
+        "/api/hosts/" + dash,                    // missing section/type
+        "/api/hosts/" + dash + "/types",          // missing type
+        "/api/hosts/" + dash + "/bogus/book",     // unknown section
+    }
+    for _, path := range cases {
+        resp, err := http.Get(srv.URL + path)
+        if err != nil {
+            t.Fatalf("GET %s: %v", path, err)
+        }
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusNotFound {
+            t.Errorf("GET %s: want 404, got %d", path, resp.StatusCode)
+        }
+    }
+}
+
+func TestHandleRawServesExistingFile(t *testing.T) {
+    root, dash, _ := newTestServerFixture(t)
+    s := NewServer(root)
+
+    rawPath := "/raw/" + filepath.ToSlash(filepath.Join(dash, "2026", "07", "25", "2026-07-25T00:00:00Z-"+dash+"-book.json"))
+    req := httptest.NewRequest(http.MethodGet, rawPath, nil)
+    w := httptest.NewRecorder()
+    s.handleRaw(w, req)
+
+    if w.Code != http.StatusOK {
+        t.Fatalf("want 200, got %d: %s", w.Code, w.Body.String())
+    }
+}
+
+func TestHandleRawRejectsTraversal(t *testing.T) {
+    root, _, _ := newTestServerFixture(t)
+    s := NewServer(root)
+
+    // Construct the request directly (bypassing ServeMux's own path
+    // cleaning) so this actually exercises handleRaw's own ".." guard.
+    u := &url.URL{Path: "/raw/../go.mod"}
+    req := httptest.NewRequest(http.MethodGet, u.String(), nil)
+    req.URL = u
+    w := httptest.NewRecorder()
+    s.handleRaw(w, req)
+
+    if w.Code != http.StatusNotFound {
+        t.Fatalf("want 404 for path traversal, got %d: %s", w.Code, w.Body.String())
+    }
+}
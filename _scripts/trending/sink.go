@@ -0,0 +1,463 @@
+package trending
+
+import (
+    "archive/tar"
+    "bytes"
+    "compress/gzip"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    awsconfig "github.com/aws/aws-sdk-go-v2/config"
+    "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Sink abstracts where fetched snapshots, summaries, and README rows are
+// persisted, so FetchAll can write to one or more destinations (local disk,
+// a tar/gzip archive, S3) without knowing the storage details.
+type Sink interface {
+    WritePayload(host, typ, ts string, data []byte) error
+    WriteSummary(host, ts string, payloads map[string]json.RawMessage) error
+    WriteREADME(host, ts string, typeEntries map[string][]entry) error
+    Close() error
+}
+
+// BuildSinks parses a slice of `-output` specs (buildkit-style, e.g.
+// "type=local,dest=./data") into their corresponding Sinks.
+func BuildSinks(ctx context.Context, specs []string) ([]Sink, error) {
+    sinks := make([]Sink, 0, len(specs))
+    for _, spec := range specs {
+        sink, err := parseOutputSpec(ctx, spec)
+        if err != nil {
+            return nil, err
+        }
+        sinks = append(sinks, sink)
+    }
+    return sinks, nil
+}
+
+// parseOutputSpec tokenizes a single `-output` spec on ',' and each token on
+// '=', matching the grammar `type=local,dest=./data`.
+func parseOutputSpec(ctx context.Context, spec string) (Sink, error) {
+    kv := map[string]string{}
+    for _, tok := range strings.Split(spec, ",") {
+        tok = strings.TrimSpace(tok)
+        if tok == "" {
+            continue
+        }
+        parts := strings.SplitN(tok, "=", 2)
+        if len(parts) != 2 {
+            return nil, fmt.Errorf("invalid -output token %q (want key=value)", tok)
+        }
+        kv[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+    }
+
+    switch kv["type"] {
+    case "", "local":
+        return newLocalSink(kv["dest"]), nil
+    case "tar":
+        return newTarSink(kv["dest"])
+    case "gzip":
+        return newGzipSink(kv["dest"]), nil
+    case "s3":
+        return newS3Sink(ctx, kv["bucket"], kv["prefix"], kv["region"])
+    default:
+        return nil, fmt.Errorf("unknown -output type %q", kv["type"])
+    }
+}
+
+// snapshotDate splits an RFC3339Nano timestamp into the y/m/d directory
+// components every sink lays its output out by.
+func snapshotDate(ts string) (y, m, d int, err error) {
+    t, err := time.Parse(time.RFC3339Nano, ts)
+    if err != nil {
+        return 0, 0, 0, fmt.Errorf("parse snapshot timestamp %q: %w", ts, err)
+    }
+    yy, mm, dd := t.Date()
+    return yy, int(mm), dd, nil
+}
+
+func snapshotDir(dash string, y, m, d int) string {
+    return filepath.Join(dash, fmt.Sprintf("%04d", y), fmt.Sprintf("%02d", m), fmt.Sprintf("%02d", d))
+}
+
+func summaryJSON(host, ts string, payloads map[string]json.RawMessage) ([]byte, error) {
+    obj := map[string]any{
+        "timestamp": ts,
+        "host":      host,
+        "types":     payloads,
+    }
+    return json.MarshalIndent(obj, "", "  ")
+}
+
+// renderREADMESection builds the "## <ts>" table for a single snapshot, in
+// the same wide-table format the per-day README.md has always used.
+func renderREADMESection(host, ts string, typeEntries map[string][]entry) string {
+    var b strings.Builder
+    b.WriteString(fmt.Sprintf("## %s\n", ts))
+    // Build a wide table: first column is row label, remaining 19 cells for items
+    itemCols := 19
+    totalCols := 1 + itemCols
+    b.WriteString("|")
+    for i := 0; i < totalCols; i++ {
+        b.WriteString("      |")
+    }
+    b.WriteString("\n|")
+    for i := 0; i < totalCols; i++ {
+        b.WriteString(" ---- |")
+    }
+    b.WriteString("\n")
+    // rows: include only types that were fetched (non-empty entries), keep stable order
+    for _, t := range Types {
+        ents, ok := typeEntries[t]
+        if !ok || len(ents) == 0 {
+            continue
+        }
+        label := typeLabel(t)
+        cells := renderCells(ents, itemCols, host, t)
+        b.WriteString("|")
+        b.WriteString(" ")
+        b.WriteString(escapePipes(label))
+        b.WriteString(" |")
+        for _, c := range cells {
+            b.WriteString(" ")
+            b.WriteString(c)
+            b.WriteString(" |")
+        }
+        b.WriteString("\n")
+    }
+    b.WriteString("\n")
+    return b.String()
+}
+
+// ---- localSink: writes one file per (host, type) under a root directory, unchanged from the original behavior ----
+
+type localSink struct {
+    root string
+}
+
+func newLocalSink(dest string) *localSink {
+    if dest == "" {
+        dest = "."
+    }
+    return &localSink{root: dest}
+}
+
+func (s *localSink) dir(host, ts string) (string, error) {
+    y, m, d, err := snapshotDate(ts)
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(s.root, snapshotDir(dashifyHost(host), y, m, d)), nil
+}
+
+func (s *localSink) WritePayload(host, typ, ts string, data []byte) error {
+    dir, err := s.dir(host, ts)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return err
+    }
+    fname := fmt.Sprintf("%s-%s-%s.json", ts, dashifyHost(host), typ)
+    return os.WriteFile(filepath.Join(dir, fname), data, 0o644)
+}
+
+func (s *localSink) WriteSummary(host, ts string, payloads map[string]json.RawMessage) error {
+    dir, err := s.dir(host, ts)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return err
+    }
+    data, err := summaryJSON(host, ts, payloads)
+    if err != nil {
+        return err
+    }
+    fname := fmt.Sprintf("%s-%s.json", ts, dashifyHost(host))
+    return os.WriteFile(filepath.Join(dir, fname), data, 0o644)
+}
+
+func (s *localSink) WriteREADME(host, ts string, typeEntries map[string][]entry) error {
+    dir, err := s.dir(host, ts)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return err
+    }
+    fpath := filepath.Join(dir, "README.md")
+
+    var b strings.Builder
+    if _, err := os.Stat(fpath); errors.Is(err, os.ErrNotExist) {
+        b.WriteString(fmt.Sprintf("# NeoDB Trending History for %s\n\n", host))
+    }
+    b.WriteString(renderREADMESection(host, ts, typeEntries))
+
+    f, err := os.OpenFile(fpath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    _, err = f.WriteString(b.String())
+    return err
+}
+
+func (s *localSink) Close() error { return nil }
+
+// ---- tarSink: streams every write into a single tar archive, handy as a CI artifact ----
+
+type tarSink struct {
+    mu  sync.Mutex
+    f   *os.File
+    tw  *tar.Writer
+    // readmes accumulates README bytes per in-archive README.md path (one per
+    // host per day) so repeated snapshots for the same host and day append into
+    // one in-archive README.md, like localSink does on disk.
+    readmes map[string]*strings.Builder
+}
+
+func newTarSink(dest string) (*tarSink, error) {
+    if dest == "" {
+        dest = "snapshot.tar"
+    }
+    f, err := os.Create(dest)
+    if err != nil {
+        return nil, err
+    }
+    return &tarSink{f: f, tw: tar.NewWriter(f), readmes: map[string]*strings.Builder{}}, nil
+}
+
+func (s *tarSink) writeEntry(name string, data []byte) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    hdr := &tar.Header{
+        Name: name,
+        Mode: 0o644,
+        Size: int64(len(data)),
+    }
+    if err := s.tw.WriteHeader(hdr); err != nil {
+        return err
+    }
+    _, err := s.tw.Write(data)
+    return err
+}
+
+func (s *tarSink) WritePayload(host, typ, ts string, data []byte) error {
+    y, m, d, err := snapshotDate(ts)
+    if err != nil {
+        return err
+    }
+    dash := dashifyHost(host)
+    name := filepath.Join(snapshotDir(dash, y, m, d), fmt.Sprintf("%s-%s-%s.json", ts, dash, typ))
+    return s.writeEntry(name, data)
+}
+
+func (s *tarSink) WriteSummary(host, ts string, payloads map[string]json.RawMessage) error {
+    y, m, d, err := snapshotDate(ts)
+    if err != nil {
+        return err
+    }
+    data, err := summaryJSON(host, ts, payloads)
+    if err != nil {
+        return err
+    }
+    dash := dashifyHost(host)
+    name := filepath.Join(snapshotDir(dash, y, m, d), fmt.Sprintf("%s-%s.json", ts, dash))
+    return s.writeEntry(name, data)
+}
+
+func (s *tarSink) WriteREADME(host, ts string, typeEntries map[string][]entry) error {
+    y, m, d, err := snapshotDate(ts)
+    if err != nil {
+        return err
+    }
+    dash := dashifyHost(host)
+    name := filepath.Join(snapshotDir(dash, y, m, d), "README.md")
+
+    s.mu.Lock()
+    b, ok := s.readmes[name]
+    if !ok {
+        b = &strings.Builder{}
+        b.WriteString(fmt.Sprintf("# NeoDB Trending History for %s\n\n", host))
+        s.readmes[name] = b
+    }
+    b.WriteString(renderREADMESection(host, ts, typeEntries))
+    s.mu.Unlock()
+    return nil
+}
+
+// Close flushes accumulated READMEs into the archive and closes the tar writer.
+func (s *tarSink) Close() error {
+    s.mu.Lock()
+    for name, b := range s.readmes {
+        hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(b.Len())}
+        if err := s.tw.WriteHeader(hdr); err != nil {
+            s.mu.Unlock()
+            return err
+        }
+        if _, err := s.tw.Write([]byte(b.String())); err != nil {
+            s.mu.Unlock()
+            return err
+        }
+    }
+    s.mu.Unlock()
+    if err := s.tw.Close(); err != nil {
+        return err
+    }
+    return s.f.Close()
+}
+
+// ---- gzipSink: localSink's layout, but every payload/summary is written as a per-file .json.gz ----
+
+type gzipSink struct {
+    local *localSink
+}
+
+func newGzipSink(dest string) *gzipSink {
+    return &gzipSink{local: newLocalSink(dest)}
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+    var buf bytes.Buffer
+    gw := gzip.NewWriter(&buf)
+    if _, err := gw.Write(data); err != nil {
+        return nil, err
+    }
+    if err := gw.Close(); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+func (s *gzipSink) WritePayload(host, typ, ts string, data []byte) error {
+    dir, err := s.local.dir(host, ts)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return err
+    }
+    gz, err := gzipBytes(data)
+    if err != nil {
+        return err
+    }
+    fname := fmt.Sprintf("%s-%s-%s.json.gz", ts, dashifyHost(host), typ)
+    return os.WriteFile(filepath.Join(dir, fname), gz, 0o644)
+}
+
+func (s *gzipSink) WriteSummary(host, ts string, payloads map[string]json.RawMessage) error {
+    dir, err := s.local.dir(host, ts)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return err
+    }
+    data, err := summaryJSON(host, ts, payloads)
+    if err != nil {
+        return err
+    }
+    gz, err := gzipBytes(data)
+    if err != nil {
+        return err
+    }
+    fname := fmt.Sprintf("%s-%s.json.gz", ts, dashifyHost(host))
+    return os.WriteFile(filepath.Join(dir, fname), gz, 0o644)
+}
+
+// WriteREADME is left uncompressed (it's Markdown meant to be browsed directly).
+func (s *gzipSink) WriteREADME(host, ts string, typeEntries map[string][]entry) error {
+    return s.local.WriteREADME(host, ts, typeEntries)
+}
+
+func (s *gzipSink) Close() error { return nil }
+
+// ---- s3Sink: uploads the same files localSink would write, as S3 objects under bucket/prefix ----
+
+type s3Sink struct {
+    ctx    context.Context
+    client *s3.Client
+    bucket string
+    prefix string
+}
+
+func newS3Sink(ctx context.Context, bucket, prefix, region string) (*s3Sink, error) {
+    if bucket == "" {
+        return nil, errors.New("-output type=s3 requires bucket=...")
+    }
+    opts := []func(*awsconfig.LoadOptions) error{}
+    if region != "" {
+        opts = append(opts, awsconfig.WithRegion(region))
+    }
+    cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+    if err != nil {
+        return nil, fmt.Errorf("load AWS config: %w", err)
+    }
+    return &s3Sink{ctx: ctx, client: s3.NewFromConfig(cfg), bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *s3Sink) key(parts ...string) string {
+    all := append([]string{}, parts...)
+    if s.prefix != "" {
+        all = append([]string{s.prefix}, all...)
+    }
+    return strings.Join(all, "/")
+}
+
+func (s *s3Sink) put(ctx context.Context, key string, data []byte) error {
+    _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+        Bucket: aws.String(s.bucket),
+        Key:    aws.String(key),
+        Body:   bytes.NewReader(data),
+    })
+    return err
+}
+
+func (s *s3Sink) WritePayload(host, typ, ts string, data []byte) error {
+    y, m, d, err := snapshotDate(ts)
+    if err != nil {
+        return err
+    }
+    dash := dashifyHost(host)
+    key := s.key(snapshotDir(dash, y, m, d), fmt.Sprintf("%s-%s-%s.json", ts, dash, typ))
+    return s.put(s.ctx, key, data)
+}
+
+func (s *s3Sink) WriteSummary(host, ts string, payloads map[string]json.RawMessage) error {
+    y, m, d, err := snapshotDate(ts)
+    if err != nil {
+        return err
+    }
+    data, err := summaryJSON(host, ts, payloads)
+    if err != nil {
+        return err
+    }
+    dash := dashifyHost(host)
+    key := s.key(snapshotDir(dash, y, m, d), fmt.Sprintf("%s-%s.json", ts, dash))
+    return s.put(s.ctx, key, data)
+}
+
+// WriteREADME uploads just this snapshot's section, overwriting any README
+// object already at that key: S3 has no cheap append, so unlike localSink
+// (across runs) and tarSink (across calls within one archive), the README is
+// not accumulated.
+func (s *s3Sink) WriteREADME(host, ts string, typeEntries map[string][]entry) error {
+    y, m, d, err := snapshotDate(ts)
+    if err != nil {
+        return err
+    }
+    dash := dashifyHost(host)
+    key := s.key(snapshotDir(dash, y, m, d), "README.md")
+    return s.put(s.ctx, key, []byte(renderREADMESection(host, ts, typeEntries)))
+}
+
+func (s *s3Sink) Close() error { return nil }
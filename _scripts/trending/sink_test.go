@@ -0,0 +1,219 @@
+package trending
+
+import (
+    "archive/tar"
+    "bytes"
+    "compress/gzip"
+    "context"
+    "encoding/json"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestLocalSinkWriteReadBack(t *testing.T) {
+    dir := t.TempDir()
+    sink := newLocalSink(dir)
+    host, ts := "example.test", "2026-07-25T00:00:00Z"
+    payload := []byte(`[{"title":"Alpha"}]`)
+
+    if err := sink.WritePayload(host, "book", ts, payload); err != nil {
+        t.Fatalf("WritePayload: %v", err)
+    }
+    want := filepath.Join(dir, "example-test", "2026", "07", "25", ts+"-example-test-book.json")
+    got, err := os.ReadFile(want)
+    if err != nil {
+        t.Fatalf("read back payload: %v", err)
+    }
+    if string(got) != string(payload) {
+        t.Errorf("payload round-trip: got %q, want %q", got, payload)
+    }
+
+    payloads := map[string]json.RawMessage{"book": payload}
+    if err := sink.WriteSummary(host, ts, payloads); err != nil {
+        t.Fatalf("WriteSummary: %v", err)
+    }
+    summaryPath := filepath.Join(dir, "example-test", "2026", "07", "25", ts+"-example-test.json")
+    if _, err := os.Stat(summaryPath); err != nil {
+        t.Errorf("summary file missing: %v", err)
+    }
+
+    entries := map[string][]entry{"book": {{Title: "Alpha"}}}
+    if err := sink.WriteREADME(host, ts, entries); err != nil {
+        t.Fatalf("WriteREADME: %v", err)
+    }
+    if err := sink.WriteREADME(host, ts, entries); err != nil {
+        t.Fatalf("WriteREADME (second call): %v", err)
+    }
+    readme, err := os.ReadFile(filepath.Join(dir, "example-test", "2026", "07", "25", "README.md"))
+    if err != nil {
+        t.Fatalf("read back README: %v", err)
+    }
+    if n := strings.Count(string(readme), "# NeoDB Trending History for"); n != 1 {
+        t.Errorf("want exactly one title header across repeated writes, got %d in %q", n, readme)
+    }
+    if n := strings.Count(string(readme), "## "+ts); n != 2 {
+        t.Errorf("want one snapshot section per WriteREADME call, got %d", n)
+    }
+}
+
+func TestTarSinkWriteReadBack(t *testing.T) {
+    dest := filepath.Join(t.TempDir(), "snapshot.tar")
+    sink, err := newTarSink(dest)
+    if err != nil {
+        t.Fatalf("newTarSink: %v", err)
+    }
+    host, ts := "example.test", "2026-07-25T00:00:00Z"
+    payload := []byte(`[{"title":"Alpha"}]`)
+
+    if err := sink.WritePayload(host, "book", ts, payload); err != nil {
+        t.Fatalf("WritePayload: %v", err)
+    }
+    if err := sink.WriteREADME(host, ts, map[string][]entry{"book": {{Title: "Alpha"}}}); err != nil {
+        t.Fatalf("WriteREADME: %v", err)
+    }
+    if err := sink.Close(); err != nil {
+        t.Fatalf("Close: %v", err)
+    }
+
+    f, err := os.Open(dest)
+    if err != nil {
+        t.Fatalf("open tar: %v", err)
+    }
+    defer f.Close()
+    tr := tar.NewReader(f)
+    found := map[string][]byte{}
+    for {
+        hdr, err := tr.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            t.Fatalf("tar.Next: %v", err)
+        }
+        data, err := io.ReadAll(tr)
+        if err != nil {
+            t.Fatalf("read tar entry %s: %v", hdr.Name, err)
+        }
+        found[hdr.Name] = data
+    }
+
+    wantPayload := filepath.Join("example-test", "2026", "07", "25", ts+"-example-test-book.json")
+    if string(found[wantPayload]) != string(payload) {
+        t.Errorf("payload entry %q: got %q, want %q", wantPayload, found[wantPayload], payload)
+    }
+    wantREADME := filepath.Join("example-test", "2026", "07", "25", "README.md")
+    readme, ok := found[wantREADME]
+    if !ok {
+        t.Fatalf("README entry %q not found among %v", wantREADME, keysOf(found))
+    }
+    if !strings.Contains(string(readme), "# NeoDB Trending History for "+host) {
+        t.Errorf("README missing title header: %q", readme)
+    }
+}
+
+func keysOf(m map[string][]byte) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    return keys
+}
+
+func TestGzipSinkWriteReadBack(t *testing.T) {
+    dir := t.TempDir()
+    sink := newGzipSink(dir)
+    host, ts := "example.test", "2026-07-25T00:00:00Z"
+    payload := []byte(`[{"title":"Alpha"}]`)
+
+    if err := sink.WritePayload(host, "book", ts, payload); err != nil {
+        t.Fatalf("WritePayload: %v", err)
+    }
+    fpath := filepath.Join(dir, "example-test", "2026", "07", "25", ts+"-example-test-book.json.gz")
+    gz, err := os.ReadFile(fpath)
+    if err != nil {
+        t.Fatalf("read back gzip payload: %v", err)
+    }
+    gr, err := gzip.NewReader(bytes.NewReader(gz))
+    if err != nil {
+        t.Fatalf("gzip.NewReader: %v", err)
+    }
+    got, err := io.ReadAll(gr)
+    if err != nil {
+        t.Fatalf("decompress: %v", err)
+    }
+    if string(got) != string(payload) {
+        t.Errorf("gzip round-trip: got %q, want %q", got, payload)
+    }
+}
+
+func TestParseOutputSpec(t *testing.T) {
+    cases := []struct {
+        name    string
+        spec    string
+        wantErr bool
+        check   func(t *testing.T, s Sink)
+    }{
+        {
+            name: "default type is local",
+            spec: "dest=" + t.TempDir(),
+            check: func(t *testing.T, s Sink) {
+                if _, ok := s.(*localSink); !ok {
+                    t.Errorf("want *localSink, got %T", s)
+                }
+            },
+        },
+        {
+            name: "explicit local",
+            spec: "type=local,dest=" + t.TempDir(),
+            check: func(t *testing.T, s Sink) {
+                if _, ok := s.(*localSink); !ok {
+                    t.Errorf("want *localSink, got %T", s)
+                }
+            },
+        },
+        {
+            name: "gzip",
+            spec: "type=gzip,dest=" + t.TempDir(),
+            check: func(t *testing.T, s Sink) {
+                if _, ok := s.(*gzipSink); !ok {
+                    t.Errorf("want *gzipSink, got %T", s)
+                }
+            },
+        },
+        {
+            name:    "bad token without '='",
+            spec:    "type=local,notakeyvalue",
+            wantErr: true,
+        },
+        {
+            name:    "unknown type",
+            spec:    "type=bogus",
+            wantErr: true,
+        },
+        {
+            name:    "s3 missing bucket",
+            spec:    "type=s3,region=us-east-1",
+            wantErr: true,
+        },
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            sink, err := parseOutputSpec(context.Background(), c.spec)
+            if c.wantErr {
+                if err == nil {
+                    t.Fatalf("parseOutputSpec(%q): want error, got nil", c.spec)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatalf("parseOutputSpec(%q): %v", c.spec, err)
+            }
+            if c.check != nil {
+                c.check(t, sink)
+            }
+        })
+    }
+}
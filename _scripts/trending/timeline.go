@@ -0,0 +1,273 @@
+package trending
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// walkDates returns every YYYY-MM-DD directory under root/host, ascending,
+// that contains at least one snapshot file. Shared by Server and BuildTimeline.
+func walkDates(root, host string) ([]string, error) {
+    var dates []string
+    years, err := os.ReadDir(filepath.Join(root, host))
+    if err != nil {
+        return nil, err
+    }
+    for _, y := range years {
+        if !y.IsDir() {
+            continue
+        }
+        months, err := os.ReadDir(filepath.Join(root, host, y.Name()))
+        if err != nil {
+            continue
+        }
+        for _, m := range months {
+            if !m.IsDir() {
+                continue
+            }
+            days, err := os.ReadDir(filepath.Join(root, host, y.Name(), m.Name()))
+            if err != nil {
+                continue
+            }
+            for _, d := range days {
+                if !d.IsDir() {
+                    continue
+                }
+                files, err := os.ReadDir(filepath.Join(root, host, y.Name(), m.Name(), d.Name()))
+                if err != nil || len(files) == 0 {
+                    continue
+                }
+                dates = append(dates, fmt.Sprintf("%s-%s-%s", y.Name(), m.Name(), d.Name()))
+            }
+        }
+    }
+    sort.Strings(dates)
+    return dates, nil
+}
+
+// walkSnapshotFiles returns the per-type JSON payload files for (host, typ) on
+// a given YYYY-MM-DD date, sorted by timestamp (filename order).
+func walkSnapshotFiles(root, host, typ, date string) ([]string, error) {
+    parts := strings.SplitN(date, "-", 3)
+    if len(parts) != 3 {
+        return nil, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", date)
+    }
+    dir := filepath.Join(root, host, parts[0], parts[1], parts[2])
+    files, err := os.ReadDir(dir)
+    if err != nil {
+        return nil, err
+    }
+    var out []string
+    suffix := fmt.Sprintf("-%s-%s.json", host, typ)
+    for _, f := range files {
+        if strings.HasSuffix(f.Name(), suffix) {
+            out = append(out, filepath.Join(dir, f.Name()))
+        }
+    }
+    sort.Strings(out)
+    return out, nil
+}
+
+// RankPoint is one sample in an item's rank-over-time series: its 1-indexed
+// position within the trending list at ts.
+type RankPoint struct {
+    TS   time.Time `json:"ts"`
+    Rank int       `json:"rank"`
+}
+
+// BuildTimeline scans the daily snapshot directories for (host, typ) between
+// from and to (inclusive), and returns each item's rank-over-time series keyed
+// by its stable id (see extractItemID).
+func BuildTimeline(root, host, typ string, from, to time.Time) (map[string][]RankPoint, error) {
+    dates, err := walkDates(root, host)
+    if err != nil {
+        return nil, fmt.Errorf("list dates for %s: %w", host, err)
+    }
+
+    timeline := map[string][]RankPoint{}
+    for _, date := range dates {
+        day, err := time.Parse("2006-01-02", date)
+        if err != nil || day.Before(truncateDay(from)) || day.After(truncateDay(to)) {
+            continue
+        }
+        files, err := walkSnapshotFiles(root, host, typ, date)
+        if err != nil {
+            continue
+        }
+        for _, fpath := range files {
+            data, err := os.ReadFile(fpath)
+            if err != nil {
+                continue
+            }
+            ts, err := snapshotTimestamp(fpath, host, typ)
+            if err != nil {
+                continue
+            }
+            for i, e := range toEntries(data, host, typ) {
+                if e.ID == "" {
+                    continue
+                }
+                timeline[e.ID] = append(timeline[e.ID], RankPoint{TS: ts, Rank: i + 1})
+            }
+        }
+    }
+    return timeline, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+    y, m, d := t.Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// snapshotTimestamp recovers the RFC3339Nano timestamp embedded in a snapshot
+// filename written by localSink: "<ts>-<dash>-<typ>.json".
+func snapshotTimestamp(fpath, host, typ string) (time.Time, error) {
+    base := filepath.Base(fpath)
+    tsStr := strings.TrimSuffix(base, fmt.Sprintf("-%s-%s.json", host, typ))
+    return time.Parse(time.RFC3339Nano, tsStr)
+}
+
+// Mover describes how an item's rank changed between two dates: OldRank/NewRank
+// are 0 when the item didn't appear in the other date's snapshot (a new entry
+// or a drop-off, respectively).
+type Mover struct {
+    ID      string
+    Title   string
+    OldRank int
+    NewRank int
+    Delta   int // positive: rank improved (moved toward #1)
+}
+
+// TopMovers compares the closest snapshots to `from` and `to` for (host, typ)
+// and returns the items whose rank changed the most, plus new entries and
+// drop-offs. New entries and drop-offs come first (most prominent rank first),
+// then the remaining items sorted by the magnitude of the change (largest first).
+func TopMovers(root, host, typ string, from, to time.Time, limit int) ([]Mover, error) {
+    fromEntries, err := snapshotNear(root, host, typ, from)
+    if err != nil {
+        return nil, fmt.Errorf("snapshot near %s: %w", from.Format("2006-01-02"), err)
+    }
+    toEntriesAt, err := snapshotNear(root, host, typ, to)
+    if err != nil {
+        return nil, fmt.Errorf("snapshot near %s: %w", to.Format("2006-01-02"), err)
+    }
+
+    oldRank := map[string]int{}
+    titles := map[string]string{}
+    for i, e := range fromEntries {
+        if e.ID == "" {
+            continue
+        }
+        oldRank[e.ID] = i + 1
+        titles[e.ID] = e.Title
+    }
+
+    seen := map[string]bool{}
+    var movers []Mover
+    for i, e := range toEntriesAt {
+        if e.ID == "" {
+            continue
+        }
+        seen[e.ID] = true
+        titles[e.ID] = e.Title
+        newRank := i + 1
+        old, ok := oldRank[e.ID]
+        if !ok {
+            movers = append(movers, Mover{ID: e.ID, Title: e.Title, NewRank: newRank})
+            continue
+        }
+        movers = append(movers, Mover{ID: e.ID, Title: e.Title, OldRank: old, NewRank: newRank, Delta: old - newRank})
+    }
+    for id, old := range oldRank {
+        if seen[id] {
+            continue
+        }
+        movers = append(movers, Mover{ID: id, Title: titles[id], OldRank: old})
+    }
+
+    // New entries and drop-offs always carry Delta == 0, so sorting on
+    // abs(Delta) alone would crowd them to the bottom and truncate them away
+    // long before any item with even a 1-rank wobble. Bucket them ahead of
+    // ordinary movers instead, most prominent rank first within each bucket.
+    sort.Slice(movers, func(i, j int) bool {
+        iNew, jNew := isNewOrDropped(movers[i]), isNewOrDropped(movers[j])
+        if iNew != jNew {
+            return iNew
+        }
+        if iNew {
+            return moverRank(movers[i]) < moverRank(movers[j])
+        }
+        return abs(movers[i].Delta) > abs(movers[j].Delta)
+    })
+    if limit > 0 && len(movers) > limit {
+        movers = movers[:limit]
+    }
+    return movers, nil
+}
+
+func isNewOrDropped(m Mover) bool {
+    return m.OldRank == 0 || m.NewRank == 0
+}
+
+// moverRank is whichever of NewRank/OldRank is set, for ranking new entries
+// and drop-offs against each other by how prominent they are.
+func moverRank(m Mover) int {
+    if m.NewRank != 0 {
+        return m.NewRank
+    }
+    return m.OldRank
+}
+
+// snapshotNear returns the entries of the snapshot whose date is closest to
+// the given date (on either side) for (host, typ), or nil if none exists yet.
+func snapshotNear(root, host, typ string, date time.Time) ([]entry, error) {
+    dates, err := walkDates(root, host)
+    if err != nil {
+        return nil, err
+    }
+    if len(dates) == 0 {
+        return nil, nil
+    }
+    target := truncateDay(date)
+    best := dates[0]
+    bestDiff := abs64(truncateDay(parseDate(best)).Sub(target))
+    for _, d := range dates[1:] {
+        diff := abs64(truncateDay(parseDate(d)).Sub(target))
+        if diff < bestDiff {
+            best, bestDiff = d, diff
+        }
+    }
+
+    files, err := walkSnapshotFiles(root, host, typ, best)
+    if err != nil || len(files) == 0 {
+        return nil, nil
+    }
+    data, err := os.ReadFile(files[len(files)-1])
+    if err != nil {
+        return nil, err
+    }
+    return toEntries(data, host, typ), nil
+}
+
+func parseDate(date string) time.Time {
+    t, _ := time.Parse("2006-01-02", date)
+    return t
+}
+
+func abs64(d time.Duration) time.Duration {
+    if d < 0 {
+        return -d
+    }
+    return d
+}
+
+func abs(n int) int {
+    if n < 0 {
+        return -n
+    }
+    return n
+}
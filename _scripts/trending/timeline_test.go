@@ -0,0 +1,114 @@
+package trending
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// writeTestSnapshot writes a minimal trending payload in the same layout
+// localSink produces, so TopMovers/snapshotNear can read it back.
+func writeTestSnapshot(t *testing.T, root, host, typ, date, ts string, items [][2]string) {
+    t.Helper()
+    parts := strings.SplitN(date, "-", 3)
+    dir := filepath.Join(root, host, parts[0], parts[1], parts[2])
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        t.Fatal(err)
+    }
+    var b strings.Builder
+    b.WriteString("[")
+    for i, it := range items {
+        if i > 0 {
+            b.WriteString(",")
+        }
+        fmt.Fprintf(&b, "{%q:%q,%q:%q}", "title", it[0], "url", it[1])
+    }
+    b.WriteString("]")
+    fname := fmt.Sprintf("%s-%s-%s.json", ts, host, typ)
+    if err := os.WriteFile(filepath.Join(dir, fname), []byte(b.String()), 0o644); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// TestTopMoversKeepsNewAndDroppedOverSmallWobbles guards against the bug
+// where new entries and drop-offs (Delta == 0) sorted to the bottom by
+// abs(Delta) and were truncated away ahead of any item with even a 1-rank
+// wobble.
+func TestTopMoversKeepsNewAndDroppedOverSmallWobbles(t *testing.T) {
+    root := t.TempDir()
+    host, typ := "example-test", "book"
+
+    writeTestSnapshot(t, root, host, typ, "2026-07-18", "2026-07-18T00:00:00Z", [][2]string{
+        {"Alpha", "/items/alpha"},
+        {"Beta", "/items/beta"},
+        {"Gone", "/items/gone"},
+    })
+    writeTestSnapshot(t, root, host, typ, "2026-07-25", "2026-07-25T00:00:00Z", [][2]string{
+        {"Beta", "/items/beta"},   // was #2, now #1: 1-rank wobble
+        {"Alpha", "/items/alpha"}, // was #1, now #2: 1-rank wobble
+        {"New", "/items/new"},     // brand-new entry
+    })
+
+    from, _ := time.Parse("2006-01-02", "2026-07-18")
+    to, _ := time.Parse("2006-01-02", "2026-07-25")
+
+    movers, err := TopMovers(root, host, typ, from, to, 2)
+    if err != nil {
+        t.Fatalf("TopMovers: %v", err)
+    }
+    if len(movers) != 2 {
+        t.Fatalf("want 2 movers (limit), got %d: %+v", len(movers), movers)
+    }
+    ids := map[string]bool{}
+    for _, m := range movers {
+        ids[m.ID] = true
+    }
+    if !ids["/items/new"] {
+        t.Errorf("want new entry /items/new kept over 1-rank wobbles, got %+v", movers)
+    }
+    if !ids["/items/gone"] {
+        t.Errorf("want dropped entry /items/gone kept over 1-rank wobbles, got %+v", movers)
+    }
+}
+
+// TestTopMoversOrdersByMagnitudeWithinMovers checks that, among ordinary
+// (non-new, non-dropped) movers, larger rank changes still sort first.
+func TestTopMoversOrdersByMagnitudeWithinMovers(t *testing.T) {
+    root := t.TempDir()
+    host, typ := "example-test2", "book"
+
+    writeTestSnapshot(t, root, host, typ, "2026-07-18", "2026-07-18T00:00:00Z", [][2]string{
+        {"A", "/items/a"},
+        {"B", "/items/b"},
+        {"C", "/items/c"},
+        {"D", "/items/d"},
+    })
+    writeTestSnapshot(t, root, host, typ, "2026-07-25", "2026-07-25T00:00:00Z", [][2]string{
+        {"D", "/items/d"}, // was #4, now #1: delta 3
+        {"C", "/items/c"}, // was #3, now #2: delta 1
+        {"A", "/items/a"}, // was #1, now #3: delta -2
+        {"B", "/items/b"}, // was #2, now #4: delta -2
+    })
+
+    from, _ := time.Parse("2006-01-02", "2026-07-18")
+    to, _ := time.Parse("2006-01-02", "2026-07-25")
+
+    movers, err := TopMovers(root, host, typ, from, to, 10)
+    if err != nil {
+        t.Fatalf("TopMovers: %v", err)
+    }
+    if len(movers) != 4 {
+        t.Fatalf("want 4 movers, got %d: %+v", len(movers), movers)
+    }
+    if movers[0].ID != "/items/d" {
+        t.Errorf("want biggest mover /items/d first, got %+v", movers)
+    }
+    for i := 1; i < len(movers); i++ {
+        if abs(movers[i-1].Delta) < abs(movers[i].Delta) {
+            t.Errorf("want movers sorted by descending |Delta|, got %+v", movers)
+        }
+    }
+}